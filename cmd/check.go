@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/shimmeris/SCFProxy/cmd/config"
+	"github.com/shimmeris/SCFProxy/sdk"
+	"github.com/shimmeris/SCFProxy/sdk/health"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check -p providers -r regions",
+	Short: "Probe deployed http/reverse proxies and report the lowest-latency healthy one",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers, err := createProviders(selectorFromFlags(cmd))
+		if err != nil {
+			return err
+		}
+
+		targets, err := checkableTargets(providers)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return errors.New("no deployed http or reverse proxy found among the selected providers, run `scfproxy deploy http` or `scfproxy deploy reverse` first")
+		}
+
+		target, _ := cmd.Flags().GetString("target")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		maxFails, _ := cmd.Flags().GetInt("max-fails")
+		listen, _ := cmd.Flags().GetString("listen")
+
+		checker := health.NewChecker(targets, target, interval, maxFails)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go checker.Run(ctx)
+
+		if listen != "" {
+			logrus.Infof("serving health status api on http://%s", listen)
+			go func() {
+				if err := http.ListenAndServe(listen, checker.Handler()); err != nil {
+					logrus.Error(err)
+				}
+			}()
+		}
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sig:
+				return nil
+			case <-ticker.C:
+				best, ok := checker.Best()
+				if !ok {
+					logrus.Warn("no healthy http proxy available")
+					continue
+				}
+				logrus.Infof("best: %s.%s %s (%s)", best.Provider, best.Region, best.API, best.Latency)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringSliceP("provider", "p", nil, "specify which cloud providers to check")
+	checkCmd.Flags().StringSliceP("region", "r", nil, "specify which regions of cloud providers to check")
+	checkCmd.Flags().StringP("config", "c", config.ProviderConfigPath, "path of provider credential file")
+	checkCmd.Flags().StringSlice("credential", nil, "explicit provider credential as provider.field=value, e.g. tencent.secret_id=AKID...")
+	checkCmd.Flags().String("credentials-file", defaultCredentialsFile(), "shared YAML credentials file, keyed by provider, consulted before the provider config YAML")
+	checkCmd.Flags().String("target", "https://www.google.com", "target URL used to measure proxy latency and liveness")
+	checkCmd.Flags().Duration("interval", 30*time.Second, "probe interval")
+	checkCmd.Flags().Int("max-fails", 3, "consecutive failed probes before a proxy is marked dead")
+	checkCmd.Flags().String("listen", "", "address to serve the JSON health status api on, e.g. 127.0.0.1:9999")
+
+	checkCmd.MarkFlagRequired("provider")
+	checkCmd.MarkFlagRequired("region")
+}
+
+// checkableTargets collects a health.Target for every deployed http and
+// reverse proxy among providers. Socks deployments have no HTTP endpoint
+// to probe (see socksCallbackPool) and are logged and left out instead of
+// silently dropped.
+func checkableTargets(providers []sdk.Provider) ([]health.Target, error) {
+	hconf, err := config.LoadHttpConfig()
+	if err != nil {
+		return nil, err
+	}
+	rconf, err := config.LoadReverseConfig()
+	if err != nil {
+		return nil, err
+	}
+	sconf, err := config.LoadSocksConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []health.Target
+	for _, p := range providers {
+		if record, ok := hconf.Get(p.Name(), p.Region()); ok && record.Api != "" {
+			targets = append(targets, health.Target{Provider: p.Name(), Region: p.Region(), API: record.Api})
+		}
+		if record, ok := rconf.Get(p.Name(), p.Region()); ok && record.Api != "" {
+			targets = append(targets, health.Target{Provider: p.Name(), Region: p.Region(), API: record.Api})
+		}
+		if _, ok := sconf.Get(p.Name(), p.Region()); ok {
+			logrus.Debugf("%s.%s is a socks deployment, which exposes no HTTP endpoint to probe; skipping", p.Name(), p.Region())
+		}
+	}
+	return targets, nil
+}
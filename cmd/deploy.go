@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -22,15 +25,45 @@ import (
 var deployCmd = &cobra.Command{
 	Use:       "deploy [http|socks|reverse] -p providers -r regions",
 	Short:     "Deploy module-specific proxies",
+	Long:      "Deploy module-specific proxies, either one module at a time via flags, or every module described by a profile with [-f/--file]",
 	ValidArgs: []string{"http", "socks", "reverse"},
-	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if file, _ := cmd.Flags().GetString("file"); file != "" {
+			return cobra.MaximumNArgs(0)(cmd, args)
+		}
+		return cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		providers, err := createProviders(cmd)
+		if file, _ := cmd.Flags().GetString("file"); file != "" {
+			profile, err := LoadProfile(file)
+			if err != nil {
+				return err
+			}
+			return profile.Deploy()
+		}
+
+		sel := selectorFromFlags(cmd)
+		if len(sel.Providers) == 0 {
+			return errors.New("missing parameter [-p/--provider]")
+		}
+		if len(sel.Regions) == 0 {
+			return errors.New("missing parameter [-r/--region]")
+		}
+
+		module := args[0]
+		if module == "reverse" {
+			origin, _ := cmd.Flags().GetString("origin")
+			if origin == "" {
+				return errors.New("missing parameter [-o/--origin]")
+			}
+			sel.Origin = origin
+		}
+
+		providers, err := createProviders(sel)
 		if err != nil {
 			return err
 		}
 
-		module := args[0]
 		switch module {
 		case "http":
 			return deployHttp(providers)
@@ -40,26 +73,12 @@ var deployCmd = &cobra.Command{
 				return errors.New("missing parameter [-a/--addr]")
 			}
 
-			key, _ := cmd.Flags().GetString("key")
-			if key == "" {
-				return errors.New("missing parameter [-k/--key]")
-			}
-			if len(key) != socks.KeyLength {
-				return errors.New(fmt.Sprintf("key must be %d bytes", socks.KeyLength))
-			}
-			if key == "random" {
-				key = randomString(socks.KeyLength)
-			}
-
+			keySeed, _ := cmd.Flags().GetString("key")
 			auth, _ := cmd.Flags().GetString("auth")
-			return deploySocks(providers, addr, key, auth)
+			return deploySocks(providers, addr, keySeed, auth)
 		case "reverse":
-			origin, _ := cmd.Flags().GetString("origin")
-			if origin == "" {
-				return errors.New("missing parameter [-o/--origin]")
-			}
 			ips, _ := cmd.Flags().GetStringSlice("ip")
-			return deployReverse(providers, origin, ips)
+			return deployReverse(providers, sel.Origin, ips)
 		}
 		return nil
 	},
@@ -67,6 +86,7 @@ var deployCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(deployCmd)
+	deployCmd.Flags().StringP("file", "f", "", "deploy every module described by this profile instead of the flags below")
 	deployCmd.Flags().StringSliceP("provider", "p", nil, "specify which cloud providers to deploy proxy")
 	deployCmd.Flags().StringSliceP("region", "r", nil, "specify which regions of cloud providers deploy proxy")
 	deployCmd.Flags().StringP("config", "c", config.ProviderConfigPath, "path of provider credential file")
@@ -80,32 +100,110 @@ func init() {
 	deployCmd.Flags().StringP("origin", "o", "", "[reverse] Address of the reverse proxy back to the source")
 	deployCmd.Flags().StringSlice("ip", nil, "[reverse] Restrict ips which can access the reverse proxy address")
 
-	deployCmd.MarkFlagRequired("provider")
-	deployCmd.MarkFlagRequired("region")
+	// locality-aware region selection, e.g. -r near:<origin>, -r nearest:3
+	deployCmd.Flags().String("geoip-db", "", "path to a MaxMind GeoLite2-City database, required to resolve near:/nearest: region selectors from a hostname. nearest:N also needs [-o/--origin], so it only works with `deploy reverse`")
+
+	// pluggable credential resolution: flag > env > shared file > provider config YAML
+	deployCmd.Flags().StringSlice("credential", nil, "explicit provider credential as provider.field=value, e.g. tencent.secret_id=AKID...")
+	deployCmd.Flags().String("credentials-file", defaultCredentialsFile(), "shared YAML credentials file, keyed by provider, consulted before the provider config YAML")
 }
 
-func createProviders(cmd *cobra.Command) ([]sdk.Provider, error) {
-	providerConfigPath, _ := cmd.Flags().GetString("config")
-	providerConfig, err := config.LoadProviderConfig(providerConfigPath)
+// ProviderSelector describes which providers and regions to target,
+// independent of whether it was sourced from CLI flags or a deploy
+// profile entry.
+type ProviderSelector struct {
+	ConfigPath string
+	Providers  []string
+	Regions    []string
+
+	// Origin is the reverse-proxy backend address, consulted by the
+	// nearest:N region selector to rank candidate regions by distance.
+	Origin string
+	// GeoIPDB is the path to a MaxMind GeoLite2-City database, required to
+	// resolve a near:/nearest: selector from a hostname rather than
+	// explicit "lat,lon" coordinates.
+	GeoIPDB string
+
+	// Credentials are explicit "provider.field=value" overrides, sourced
+	// from a repeatable -credential flag.
+	Credentials []string
+	// CredentialsFile is a shared YAML credentials file, keyed by
+	// provider, consulted before falling back to the provider config YAML.
+	CredentialsFile string
+}
+
+// selectorFromFlags builds a ProviderSelector out of the [-p/-r/-c] flags
+// shared by deploy, check and serve.
+func selectorFromFlags(cmd *cobra.Command) ProviderSelector {
+	configPath, _ := cmd.Flags().GetString("config")
+	providers, _ := cmd.Flags().GetStringSlice("provider")
+	regions, _ := cmd.Flags().GetStringSlice("region")
+	geoipDB, _ := cmd.Flags().GetString("geoip-db")
+	credentials, _ := cmd.Flags().GetStringSlice("credential")
+	credentialsFile, _ := cmd.Flags().GetString("credentials-file")
+	return ProviderSelector{
+		ConfigPath:      configPath,
+		Providers:       providers,
+		Regions:         regions,
+		GeoIPDB:         geoipDB,
+		Credentials:     credentials,
+		CredentialsFile: credentialsFile,
+	}
+}
+
+// defaultCredentialsFile is the default location of the shared
+// credentials file consulted by the env > file > config resolution chain.
+func defaultCredentialsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".scfproxy", "credentials.yaml")
+}
+
+// resolveSocksKey validates a [-k/--key] value and, if it's the "random"
+// sentinel, generates a fresh one.
+func resolveSocksKey(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("missing parameter [-k/--key]")
+	}
+	if len(key) != socks.KeyLength {
+		return "", errors.New(fmt.Sprintf("key must be %d bytes", socks.KeyLength))
+	}
+	if key == "random" {
+		key = randomString(socks.KeyLength)
+	}
+	return key, nil
+}
+
+func createProviders(sel ProviderSelector) ([]sdk.Provider, error) {
+	providerConfig, err := config.LoadProviderConfig(sel.ConfigPath)
 	if err != nil {
 		return nil, err
 	}
 
-	providerNames, _ := cmd.Flags().GetStringSlice("provider")
-	regionPatterns, _ := cmd.Flags().GetStringSlice("region")
+	resolver := sdk.NewChainResolver(
+		sdk.NewFlagResolver(sel.Credentials),
+		sdk.EnvResolver{},
+		sdk.NewFileResolver(sel.CredentialsFile),
+	)
+
 	var providers []sdk.Provider
-	for _, p := range providerNames {
+	for _, p := range sel.Providers {
 		if !slices.Contains(allProviders, p) {
 			logrus.Errorf("%s is not a valid provider", p)
 			continue
 		}
 
-		if !providerConfig.IsSet(p) {
+		if cred, ok := resolver.Resolve(p); ok {
+			logrus.Infof("%s credentials resolved from %s", p, cred.Source)
+			providerConfig.Set(p, cred.Raw)
+		} else if !providerConfig.IsSet(p) {
 			logrus.Warningf("%s's credential config not set, will ignore", p)
 			continue
 		}
 
-		regions := parseRegionPatterns(p, regionPatterns)
+		regions := parseRegionPatterns(p, sel.Regions, sel.Origin, sel.GeoIPDB)
 		if len(regions) == 0 {
 			logrus.Error("No region avalible, pleast use list cmd to ")
 			continue
@@ -123,9 +221,14 @@ func createProviders(cmd *cobra.Command) ([]sdk.Provider, error) {
 	return providers, nil
 }
 
-func parseRegionPatterns(provider string, regionPatterns []string) []string {
+// parseRegionPatterns resolves a set of -r/--region patterns to concrete
+// region codes for provider. origin and geoipDB are only consulted by the
+// nearest:N locality selector.
+func parseRegionPatterns(provider string, regionPatterns []string, origin, geoipDB string) []string {
 	// patter support 4 styles
 	// *, ap-*, us-3, us-north-1, ap-beijing
+	// plus 3 locality-aware styles
+	// continent:asia, near:<origin-url-or-lat,lon>, nearest:3
 	var usableRegions []string
 	regions := listRegions(provider)
 
@@ -135,6 +238,42 @@ func parseRegionPatterns(provider string, regionPatterns []string) []string {
 			break
 		}
 
+		if strings.HasPrefix(pattern, "continent:") {
+			continent := strings.TrimPrefix(pattern, "continent:")
+			usableRegions = append(usableRegions, regionsByContinent(provider, regions, continent)...)
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "near:") {
+			target := strings.TrimPrefix(pattern, "near:")
+			to, err := resolveLocality(target, geoipDB)
+			if err != nil {
+				logrus.Debugf("%s: %s", pattern, err)
+				continue
+			}
+			usableRegions = append(usableRegions, nearestRegions(provider, regions, to, 1)...)
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "nearest:") {
+			if origin == "" {
+				logrus.Warnf("%s requires [-o/--origin] to be set, which only `deploy reverse` does - ignoring this region pattern", pattern)
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(pattern, "nearest:"))
+			if err != nil {
+				logrus.Debugf("%s is not a valid nearest:N pattern", pattern)
+				continue
+			}
+			to, err := resolveLocality(origin, geoipDB)
+			if err != nil {
+				logrus.Debugf("%s: %s", pattern, err)
+				continue
+			}
+			usableRegions = append(usableRegions, nearestRegions(provider, regions, to, n)...)
+			continue
+		}
+
 		// parse specific region name like ap-hongkong-1, cn-hangzhou
 		if slices.Contains(regions, pattern) {
 			usableRegions = append(usableRegions, pattern)
@@ -200,6 +339,8 @@ func deployHttp(providers []sdk.Provider) error {
 		return err
 	}
 
+	summary := newDeploySummary("http")
+
 	var wg sync.WaitGroup
 	wg.Add(len(providers))
 
@@ -207,16 +348,20 @@ func deployHttp(providers []sdk.Provider) error {
 		go func(p sdk.Provider) {
 			defer wg.Done()
 			provider, region := p.Name(), p.Region()
+			log := logrus.WithFields(logrus.Fields{"module": "http", "provider": provider, "region": region})
+
 			hp, ok := p.(sdk.HttpProxyProvider)
 			if !ok {
-				logrus.Errorf("Provider %s can't deploy http", p.Name())
+				log.Error("provider can't deploy http")
+				summary.recordFailure(provider, region)
 				return
 			}
 
 			onlyTrigger := false
 			if record, ok := hconf.Get(provider, region); ok {
 				if record.Api != "" {
-					logrus.Infof("%s %s has been deployed, pass", provider, region)
+					log.Info("already deployed, skipping")
+					summary.recordSuccess(provider)
 					return
 				}
 				onlyTrigger = true
@@ -227,28 +372,45 @@ func deployHttp(providers []sdk.Provider) error {
 				TriggerName:  HTTPTriggerName,
 				OnlyTrigger:  onlyTrigger,
 			}
+
+			start := time.Now()
 			r, err := hp.DeployHttpProxy(opts)
+			latencyMs := time.Since(start).Milliseconds()
 			if err != nil {
-				logrus.Error(err)
+				log.WithFields(logrus.Fields{"status": "failed", "latency_ms": latencyMs, "error": err}).Error("http proxy deploy failed")
+				summary.recordFailure(provider, region)
 				return
 			}
 
-			logrus.Printf("[success] http proxy deployed in %s.%s", provider, region)
+			log.WithFields(logrus.Fields{"status": "success", "api": r.API, "latency_ms": latencyMs}).Info("http proxy deployed")
+			summary.recordSuccess(provider)
 			hconf.Set(r.Provider, r.Region, &config.HttpRecord{Api: r.API})
 		}(p)
 	}
 
 	wg.Wait()
+	summary.log()
 	return hconf.Save()
-
 }
 
-func deploySocks(providers []sdk.Provider, addr, key, auth string) error {
+// deploySocks deploys each provider's socks function with its own
+// freshly-resolved key, even when keySeed is the "random" sentinel shared
+// across every provider/region in this call: `serve socks`'s callback
+// pool tells backends apart by their deploy-time key, so reusing one key
+// across a multi-region deploy would make it ambiguous which backend
+// actually dialed back in.
+func deploySocks(providers []sdk.Provider, addr, keySeed, auth string) error {
 	sconf, err := config.LoadSocksConfig()
 	if err != nil {
 		return err
 	}
 
+	if keySeed != "random" && len(providers) > 1 {
+		logrus.Warnf("deploying %d socks backends with the same explicit [-k/--key]; `serve socks` can't tell them apart if it needs to route a dial-back - pass [-k random] (the default) for a multi-region deploy", len(providers))
+	}
+
+	summary := newDeploySummary("socks")
+
 	var wg sync.WaitGroup
 	wg.Add(len(providers))
 
@@ -256,21 +418,32 @@ func deploySocks(providers []sdk.Provider, addr, key, auth string) error {
 		go func(p sdk.Provider) {
 			defer wg.Done()
 			provider, region := p.Name(), p.Region()
+			log := logrus.WithFields(logrus.Fields{"module": "socks", "provider": provider, "region": region})
+
 			sp, ok := p.(sdk.SocksProxyProvider)
 			if !ok {
-				logrus.Errorf("Provider %s can't deploy socks", provider)
+				log.Error("provider can't deploy socks")
+				summary.recordFailure(provider, region)
 				return
 			}
 
 			onlyTrigger := false
 			if record, ok := sconf.Get(provider, region); ok {
 				if record.Key != "" {
-					logrus.Infof("%s %s has already been deployed", provider, region)
+					log.Info("already deployed, skipping")
+					summary.recordSuccess(provider)
 					return
 				}
 				onlyTrigger = true
 			}
 
+			key, err := resolveSocksKey(keySeed)
+			if err != nil {
+				log.Error(err)
+				summary.recordFailure(provider, region)
+				return
+			}
+
 			opts := &sdk.SocksProxyOpts{
 				FunctionName: SocksFunctionName,
 				TriggerName:  SocksTriggerName,
@@ -279,18 +452,25 @@ func deploySocks(providers []sdk.Provider, addr, key, auth string) error {
 				Addr:         addr,
 				Auth:         auth,
 			}
-			if err := sp.DeploySocksProxy(opts); err != nil {
-				logrus.Error(err)
+
+			start := time.Now()
+			err = sp.DeploySocksProxy(opts)
+			latencyMs := time.Since(start).Milliseconds()
+			if err != nil {
+				log.WithFields(logrus.Fields{"status": "failed", "latency_ms": latencyMs, "error": err}).Error("socks proxy deploy failed")
+				summary.recordFailure(provider, region)
 				return
 			}
 
-			logrus.Printf("[success] socks proxy deployed in %s.%s", provider, region)
+			log.WithFields(logrus.Fields{"status": "success", "latency_ms": latencyMs}).Info("socks proxy deployed")
+			summary.recordSuccess(provider)
 			tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
-			sconf.Set(sp.Name(), sp.Region(), &config.SocksRecord{Key: key, Host: tcpAddr.IP.String(), Port: tcpAddr.Port})
+			sconf.Set(sp.Name(), sp.Region(), &config.SocksRecord{Key: key, Host: tcpAddr.IP.String(), Port: tcpAddr.Port, Auth: auth})
 		}(p)
 	}
 
 	wg.Wait()
+	summary.log()
 	return sconf.Save()
 }
 
@@ -300,6 +480,8 @@ func deployReverse(providers []sdk.Provider, origin string, ips []string) error
 		return err
 	}
 
+	summary := newDeploySummary("reverse")
+
 	var wg sync.WaitGroup
 	wg.Add(len(providers))
 
@@ -309,16 +491,24 @@ func deployReverse(providers []sdk.Provider, origin string, ips []string) error
 	for _, p := range providers {
 		go func(p sdk.Provider) {
 			defer wg.Done()
+			provider, region := p.Name(), p.Region()
+			log := logrus.WithFields(logrus.Fields{"module": "reverse", "provider": provider, "region": region})
+
 			rp, ok := p.(sdk.ReverseProxyProvider)
 			if !ok {
-				logrus.Errorf("%s can't deploy reverse proxy", p.Name())
+				log.Error("provider can't deploy reverse proxy")
+				summary.recordFailure(provider, region)
 				return
 			}
 
 			opts := &sdk.ReverseProxyOpts{Origin: origin, Ips: ips}
+
+			start := time.Now()
 			r, err := rp.DeployReverseProxy(opts)
+			latencyMs := time.Since(start).Milliseconds()
 			if err != nil {
-				logrus.Error(err)
+				log.WithFields(logrus.Fields{"status": "failed", "latency_ms": latencyMs, "error": err}).Error("reverse proxy deploy failed")
+				summary.recordFailure(provider, region)
 				return
 			}
 
@@ -339,11 +529,14 @@ func deployReverse(providers []sdk.Provider, origin string, ips []string) error
 				Ips:       ips,
 			}
 			rconf.Add(record)
-			logrus.Infof("[success] %s.%s: %s - %s : accessible from %v", rp.Name(), rp.Region(), r.Origin, api, whitelistIp)
+
+			log.WithFields(logrus.Fields{"status": "success", "api": api, "latency_ms": latencyMs, "whitelist": whitelistIp}).Info("reverse proxy deployed")
+			summary.recordSuccess(provider)
 		}(p)
 	}
 
 	wg.Wait()
+	summary.log()
 	return rconf.Save()
 }
 
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	geoip2 "github.com/oschwald/geoip2-golang"
+)
+
+// coordinate is a point on Earth's surface, used to rank regions by
+// great-circle distance for the near:/nearest: region selectors.
+type coordinate struct {
+	Lat float64
+	Lon float64
+}
+
+type localityInfo struct {
+	Continent string
+	Country   string
+	coordinate
+}
+
+// regionLocality is a best-effort static table mapping each provider's
+// region code to its continent, country and approximate coordinates. It
+// only needs to be accurate enough to rank regions by distance, not to
+// pinpoint a datacenter; extend it as providers add regions.
+var regionLocality = map[string]map[string]localityInfo{
+	"tencent": {
+		"ap-guangzhou":     {Continent: "asia", Country: "cn", coordinate: coordinate{23.13, 113.26}},
+		"ap-shanghai":      {Continent: "asia", Country: "cn", coordinate: coordinate{31.23, 121.47}},
+		"ap-beijing":       {Continent: "asia", Country: "cn", coordinate: coordinate{39.90, 116.41}},
+		"ap-chengdu":       {Continent: "asia", Country: "cn", coordinate: coordinate{30.57, 104.07}},
+		"ap-hongkong":      {Continent: "asia", Country: "hk", coordinate: coordinate{22.32, 114.17}},
+		"ap-singapore":     {Continent: "asia", Country: "sg", coordinate: coordinate{1.35, 103.82}},
+		"ap-tokyo":         {Continent: "asia", Country: "jp", coordinate: coordinate{35.68, 139.69}},
+		"na-siliconvalley": {Continent: "northamerica", Country: "us", coordinate: coordinate{37.39, -122.08}},
+		"eu-frankfurt":     {Continent: "europe", Country: "de", coordinate: coordinate{50.11, 8.68}},
+	},
+	"aliyun": {
+		"cn-hangzhou":    {Continent: "asia", Country: "cn", coordinate: coordinate{30.27, 120.15}},
+		"cn-shanghai":    {Continent: "asia", Country: "cn", coordinate: coordinate{31.23, 121.47}},
+		"cn-beijing":     {Continent: "asia", Country: "cn", coordinate: coordinate{39.90, 116.41}},
+		"cn-hongkong":    {Continent: "asia", Country: "hk", coordinate: coordinate{22.32, 114.17}},
+		"ap-southeast-1": {Continent: "asia", Country: "sg", coordinate: coordinate{1.35, 103.82}},
+		"us-west-1":      {Continent: "northamerica", Country: "us", coordinate: coordinate{37.39, -122.08}},
+		"eu-central-1":   {Continent: "europe", Country: "de", coordinate: coordinate{50.11, 8.68}},
+	},
+	"aws": {
+		"us-east-1":      {Continent: "northamerica", Country: "us", coordinate: coordinate{39.04, -77.49}},
+		"us-west-2":      {Continent: "northamerica", Country: "us", coordinate: coordinate{45.84, -119.7}},
+		"ap-southeast-1": {Continent: "asia", Country: "sg", coordinate: coordinate{1.35, 103.82}},
+		"ap-northeast-1": {Continent: "asia", Country: "jp", coordinate: coordinate{35.68, 139.69}},
+		"eu-central-1":   {Continent: "europe", Country: "de", coordinate: coordinate{50.11, 8.68}},
+	},
+}
+
+// regionsByContinent returns every region of provider, among regions,
+// that regionLocality maps to continent.
+func regionsByContinent(provider string, regions []string, continent string) []string {
+	table := regionLocality[provider]
+
+	var matched []string
+	for _, r := range regions {
+		if info, ok := table[r]; ok && info.Continent == continent {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// nearestRegions returns the n regions of provider, among regions, with
+// the smallest great-circle distance to to.
+func nearestRegions(provider string, regions []string, to coordinate, n int) []string {
+	table := regionLocality[provider]
+
+	type candidate struct {
+		region   string
+		distance float64
+	}
+	var candidates []candidate
+	for _, r := range regions {
+		info, ok := table[r]
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, candidate{region: r, distance: haversine(to, info.coordinate)})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	nearest := make([]string, n)
+	for i := 0; i < n; i++ {
+		nearest[i] = candidates[i].region
+	}
+	return nearest
+}
+
+// haversine returns the great-circle distance between a and b, in
+// kilometers.
+func haversine(a, b coordinate) float64 {
+	const earthRadiusKm = 6371
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.Lat - a.Lat)
+	dLon := toRad(b.Lon - a.Lon)
+	lat1 := toRad(a.Lat)
+	lat2 := toRad(b.Lat)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// resolveLocality turns target into a coordinate. target is either
+// explicit "lat,lon" coordinates, or a URL/hostname to resolve via DNS and
+// then a GeoIP lookup against geoipDB.
+func resolveLocality(target, geoipDB string) (coordinate, error) {
+	if c, ok := parseCoordinate(target); ok {
+		return c, nil
+	}
+
+	host := target
+	if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return coordinate{}, fmt.Errorf("resolve %s: %w", target, err)
+	}
+
+	if geoipDB == "" {
+		return coordinate{}, errors.New("near:/nearest: selectors require [--geoip-db] pointing at a MaxMind GeoLite2-City database, or explicit \"lat,lon\" coordinates")
+	}
+
+	return lookupGeoIP(geoipDB, ips[0])
+}
+
+func parseCoordinate(s string) (coordinate, bool) {
+	parts := strings.SplitN(s, ",", 2)
+	if len(parts) != 2 {
+		return coordinate{}, false
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return coordinate{}, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return coordinate{}, false
+	}
+	return coordinate{Lat: lat, Lon: lon}, true
+}
+
+func lookupGeoIP(dbPath string, ip net.IP) (coordinate, error) {
+	db, err := geoip2.Open(dbPath)
+	if err != nil {
+		return coordinate{}, err
+	}
+	defer db.Close()
+
+	record, err := db.City(ip)
+	if err != nil {
+		return coordinate{}, err
+	}
+	return coordinate{Lat: record.Location.Latitude, Lon: record.Location.Longitude}, nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import "testing"
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        coordinate
+		wantKm      float64
+		toleranceKm float64
+	}{
+		{name: "same point", a: coordinate{Lat: 1.35, Lon: 103.82}, b: coordinate{Lat: 1.35, Lon: 103.82}, wantKm: 0, toleranceKm: 0.01},
+		{name: "singapore to tokyo", a: coordinate{Lat: 1.35, Lon: 103.82}, b: coordinate{Lat: 35.68, Lon: 139.69}, wantKm: 5300, toleranceKm: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversine(tt.a, tt.b)
+			if diff := got - tt.wantKm; diff < -tt.toleranceKm || diff > tt.toleranceKm {
+				t.Errorf("haversine(%v, %v) = %v, want within %v of %v", tt.a, tt.b, got, tt.toleranceKm, tt.wantKm)
+			}
+		})
+	}
+}
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want coordinate
+		ok   bool
+	}{
+		{name: "valid", in: "1.35,103.82", want: coordinate{Lat: 1.35, Lon: 103.82}, ok: true},
+		{name: "valid with spaces", in: " 1.35 , 103.82 ", want: coordinate{Lat: 1.35, Lon: 103.82}, ok: true},
+		{name: "negative", in: "-33.87,151.21", want: coordinate{Lat: -33.87, Lon: 151.21}, ok: true},
+		{name: "hostname", in: "example.com", ok: false},
+		{name: "missing lon", in: "1.35", ok: false},
+		{name: "non-numeric", in: "a,b", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCoordinate(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("parseCoordinate(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseCoordinate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionsByContinent(t *testing.T) {
+	regions := []string{"ap-guangzhou", "ap-tokyo", "na-siliconvalley", "eu-frankfurt"}
+
+	got := regionsByContinent("tencent", regions, "asia")
+	want := map[string]bool{"ap-guangzhou": true, "ap-tokyo": true}
+	if len(got) != len(want) {
+		t.Fatalf("regionsByContinent(asia) = %v, want 2 asia regions", got)
+	}
+	for _, r := range got {
+		if !want[r] {
+			t.Errorf("regionsByContinent(asia) returned unexpected region %q", r)
+		}
+	}
+}
+
+func TestNearestRegions(t *testing.T) {
+	regions := []string{"ap-guangzhou", "ap-tokyo", "na-siliconvalley", "eu-frankfurt"}
+	// roughly Guangzhou's coordinates
+	to := coordinate{Lat: 23.13, Lon: 113.26}
+
+	got := nearestRegions("tencent", regions, to, 2)
+	if len(got) != 2 {
+		t.Fatalf("nearestRegions(n=2) returned %d regions, want 2", len(got))
+	}
+	if got[0] != "ap-guangzhou" {
+		t.Errorf("nearestRegions(n=2)[0] = %q, want ap-guangzhou (the exact match)", got[0])
+	}
+
+	if got := nearestRegions("tencent", regions, to, 100); len(got) != len(regions) {
+		t.Errorf("nearestRegions(n=100) = %d regions, want all %d clamped", len(got), len(regions))
+	}
+}
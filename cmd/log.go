@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("log-format", "text", "log output format: text or json")
+	rootCmd.PersistentFlags().String("log-level", "info", "log level: trace, debug, info, warning, error, fatal, panic")
+
+	cobra.OnInitialize(configureLogging)
+}
+
+// configureLogging applies the [--log-format/--log-level] persistent
+// flags before any command runs, so a deploy run's structured records can
+// feed a pipeline instead of only being grep-able text.
+func configureLogging() {
+	format, _ := rootCmd.PersistentFlags().GetString("log-format")
+	if format == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	}
+
+	level, _ := rootCmd.PersistentFlags().GetString("log-level")
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		logrus.Warnf("invalid log level %q, defaulting to info", level)
+		lvl = logrus.InfoLevel
+	}
+	logrus.SetLevel(lvl)
+}
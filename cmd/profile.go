@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/shimmeris/SCFProxy/cmd/config"
+)
+
+// Profile is the declarative alternative to driving `scfproxy deploy`
+// through flags: it describes every module to deploy in one shot, so a
+// multi-provider, multi-module rollout can be checked into git and
+// redeployed reproducibly, e.g. from CI.
+type Profile struct {
+	ProviderConfig  string        `yaml:"provider_config"`
+	GeoIPDB         string        `yaml:"geoip_db"`
+	Credentials     []string      `yaml:"credentials"`
+	CredentialsFile string        `yaml:"credentials_file"`
+	Http            []HttpSpec    `yaml:"http"`
+	Socks           []SocksSpec   `yaml:"socks"`
+	Reverse         []ReverseSpec `yaml:"reverse"`
+}
+
+// Selector is the part of a deploy spec shared by every module: which
+// providers, and which of their regions, to target.
+type Selector struct {
+	Providers []string `yaml:"providers"`
+	Regions   []string `yaml:"regions"`
+}
+
+// HttpSpec describes one `deploy http` invocation.
+type HttpSpec struct {
+	Selector `yaml:",inline"`
+}
+
+// SocksSpec describes one `deploy socks` invocation.
+type SocksSpec struct {
+	Selector `yaml:",inline"`
+	Addr     string `yaml:"addr"`
+	Key      string `yaml:"key"`
+	Auth     string `yaml:"auth"`
+}
+
+// ReverseSpec describes one `deploy reverse` invocation.
+type ReverseSpec struct {
+	Selector `yaml:",inline"`
+	Origin   string   `yaml:"origin"`
+	Ips      []string `yaml:"ips"`
+}
+
+// LoadProfile reads and parses a deploy profile from path.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("parse profile %s: %w", path, err)
+	}
+	return &profile, nil
+}
+
+// Deploy executes every module described by the profile, logging and
+// continuing past a failing entry instead of aborting the whole profile.
+func (p *Profile) Deploy() error {
+	configPath := p.ProviderConfig
+	if configPath == "" {
+		configPath = config.ProviderConfigPath
+	}
+
+	credentialsFile := p.CredentialsFile
+	if credentialsFile == "" {
+		credentialsFile = defaultCredentialsFile()
+	}
+
+	for _, spec := range p.Http {
+		providers, err := createProviders(ProviderSelector{
+			ConfigPath:      configPath,
+			Providers:       spec.Providers,
+			Regions:         spec.Regions,
+			GeoIPDB:         p.GeoIPDB,
+			Credentials:     p.Credentials,
+			CredentialsFile: credentialsFile,
+		})
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		if err := deployHttp(providers); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	for _, spec := range p.Socks {
+		providers, err := createProviders(ProviderSelector{
+			ConfigPath:      configPath,
+			Providers:       spec.Providers,
+			Regions:         spec.Regions,
+			GeoIPDB:         p.GeoIPDB,
+			Credentials:     p.Credentials,
+			CredentialsFile: credentialsFile,
+		})
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+
+		keySeed := spec.Key
+		if keySeed == "" {
+			keySeed = "random"
+		}
+
+		if err := deploySocks(providers, spec.Addr, keySeed, spec.Auth); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	for _, spec := range p.Reverse {
+		providers, err := createProviders(ProviderSelector{
+			ConfigPath:      configPath,
+			Providers:       spec.Providers,
+			Regions:         spec.Regions,
+			Origin:          spec.Origin,
+			GeoIPDB:         p.GeoIPDB,
+			Credentials:     p.Credentials,
+			CredentialsFile: credentialsFile,
+		})
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		if err := deployReverse(providers, spec.Origin, spec.Ips); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return nil
+}
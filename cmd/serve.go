@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/shimmeris/SCFProxy/cmd/config"
+	"github.com/shimmeris/SCFProxy/sdk"
+	"github.com/shimmeris/SCFProxy/sdk/dispatch"
+	"github.com/shimmeris/SCFProxy/sdk/health"
+)
+
+var serveCmd = &cobra.Command{
+	Use:       "serve [http|socks] -p providers -r regions",
+	Short:     "Aggregate deployed proxies behind a single local listener",
+	ValidArgs: []string{"http", "socks"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers, err := createProviders(selectorFromFlags(cmd))
+		if err != nil {
+			return err
+		}
+
+		listen, _ := cmd.Flags().GetString("listen")
+		mode, _ := cmd.Flags().GetString("mode")
+
+		switch args[0] {
+		case "http":
+			return serveHttp(providers, listen, dispatch.Mode(mode))
+		case "socks":
+			return serveSocks(providers, listen, dispatch.Mode(mode))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringSliceP("provider", "p", nil, "specify which cloud providers to aggregate")
+	serveCmd.Flags().StringSliceP("region", "r", nil, "specify which regions of cloud providers to aggregate")
+	serveCmd.Flags().StringP("config", "c", config.ProviderConfigPath, "path of provider credential file")
+	serveCmd.Flags().StringSlice("credential", nil, "explicit provider credential as provider.field=value, e.g. tencent.secret_id=AKID...")
+	serveCmd.Flags().String("credentials-file", defaultCredentialsFile(), "shared YAML credentials file, keyed by provider, consulted before the provider config YAML")
+	serveCmd.Flags().StringP("listen", "l", "127.0.0.1:1080", "local address to listen on")
+	serveCmd.Flags().StringP("mode", "m", string(dispatch.RoundRobin), "dispatch mode: round-robin, random, sticky or latency")
+
+	serveCmd.MarkFlagRequired("provider")
+	serveCmd.MarkFlagRequired("region")
+}
+
+func serveHttp(providers []sdk.Provider, listen string, mode dispatch.Mode) error {
+	hconf, err := config.LoadHttpConfig()
+	if err != nil {
+		return err
+	}
+
+	var backends []dispatch.Backend
+	for _, p := range providers {
+		record, ok := hconf.Get(p.Name(), p.Region())
+		if !ok || record.Api == "" {
+			continue
+		}
+		backends = append(backends, dispatch.Backend{Provider: p.Name(), Region: p.Region(), Addr: record.Api})
+	}
+	if len(backends) == 0 {
+		return errors.New("no deployed http proxy found among the selected providers, run `scfproxy deploy http` first")
+	}
+
+	picker := dispatch.NewPicker(mode, backends, newLatencyChecker(mode, backends))
+
+	rp := &httputil.ReverseProxy{
+		// The deployed http-proxy function exposes only its own endpoint,
+		// not the requested site, so the real target is passed in a
+		// header rather than in the request line - the same
+		// X-Scfproxy-Target convention health.Checker probes it with.
+		Director: func(req *http.Request) {
+			backend, _ := picker.Pick(req.Host)
+			target, err := url.Parse(backend.Addr)
+			if err != nil {
+				return
+			}
+
+			original := *req.URL
+			if original.Scheme == "" {
+				original.Scheme = "http"
+				original.Host = req.Host
+			}
+			req.Header.Set("X-Scfproxy-Target", original.String())
+
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = target.Path
+			req.Host = target.Host
+		},
+	}
+
+	logrus.Infof("serving http aggregator on http://%s (%d backends, mode=%s)", listen, len(backends), mode)
+	return http.ListenAndServe(listen, rp)
+}
+
+func serveSocks(providers []sdk.Provider, listen string, mode dispatch.Mode) error {
+	// Unlike the http module, a socks backend exposes no HTTP endpoint of
+	// its own to probe - it dials back to us - so there's nothing for a
+	// health.Checker to measure latency against.
+	if mode == dispatch.Latency {
+		return errors.New("[-m/--mode] latency isn't supported for `serve socks`: the socks module has no HTTP endpoint to probe, use round-robin, random or sticky instead")
+	}
+
+	sconf, err := config.LoadSocksConfig()
+	if err != nil {
+		return err
+	}
+
+	var backends []dispatch.Backend
+	records := map[string]*config.SocksRecord{}
+	for _, p := range providers {
+		record, ok := sconf.Get(p.Name(), p.Region())
+		if !ok || record.Host == "" {
+			continue
+		}
+		backends = append(backends, dispatch.Backend{
+			Provider: p.Name(),
+			Region:   p.Region(),
+			Addr:     net.JoinHostPort(record.Host, strconv.Itoa(record.Port)),
+			Auth:     record.Auth,
+		})
+		records[backendKey(p.Name(), p.Region())] = record
+	}
+	if len(backends) == 0 {
+		return errors.New("no deployed socks proxy found among the selected providers, run `scfproxy deploy socks` first")
+	}
+
+	// See socksCallbackPool for why serving socks means accepting a
+	// connection, not dialing one.
+	pool := newSocksCallbackPool(records)
+	if err := pool.Listen(); err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	picker := dispatch.NewPicker(mode, backends, nil)
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	logrus.Infof("serving socks5 aggregator on socks5://%s (%d backends, mode=%s)", listen, len(backends), mode)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		go relaySocksConn(conn, picker, pool)
+	}
+}
+
+// relaySocksConn terminates the client's SOCKS5 handshake locally, waits
+// for whichever upstream backend the picker chose (keyed on the
+// requested target, so sticky mode actually sticks) to dial its callback
+// back in, then drives the SOCKS5 client side of that connection to reach
+// target, passing along its auth if one was collected at deploy time.
+func relaySocksConn(conn net.Conn, picker *dispatch.Picker, pool *socksCallbackPool) {
+	defer conn.Close()
+
+	target, err := socksHandshake(conn)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	backend, ok := picker.Pick(target)
+	if !ok {
+		return
+	}
+
+	upstream, err := pool.Take(backend.Provider, backend.Region)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	defer upstream.Close()
+
+	var auth *socksAuth
+	if backend.Auth != "" {
+		if user, pass, found := strings.Cut(backend.Auth, ":"); found {
+			auth = &socksAuth{User: user, Password: pass}
+		}
+	}
+
+	if err := socksClientConnect(upstream, target, auth); err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(upstream, conn)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(conn, upstream)
+	}()
+	wg.Wait()
+}
+
+func newLatencyChecker(mode dispatch.Mode, backends []dispatch.Backend) *health.Checker {
+	if mode != dispatch.Latency {
+		return nil
+	}
+
+	var targets []health.Target
+	for _, b := range backends {
+		targets = append(targets, health.Target{Provider: b.Provider, Region: b.Region, API: b.Addr})
+	}
+
+	checker := health.NewChecker(targets, "https://www.google.com", 30*time.Second, 3)
+	go checker.Run(context.Background())
+	return checker
+}
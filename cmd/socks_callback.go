@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/shimmeris/SCFProxy/cmd/config"
+	"github.com/shimmeris/SCFProxy/socks"
+)
+
+// callbackWaitTimeout bounds how long relaySocksConn will wait for the
+// matching deployed function to dial back in before giving up on a
+// client connection.
+const callbackWaitTimeout = 15 * time.Second
+
+// socksCallbackPool listens on the [-a/--addr] address every `deploy
+// socks` backend was given at deploy time, and hands each cloud
+// function's reverse connection to whichever client session picked that
+// backend. The deployed socks function never runs a public SOCKS5
+// server - it dials out to us, authenticating with its deploy-time key -
+// so `serve socks` has to accept that dial-back before it has anything to
+// relay through.
+type socksCallbackPool struct {
+	records map[string]*config.SocksRecord // backendKey(provider, region) -> deploy record
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	waiting   map[string]chan net.Conn // backendKey(provider, region) -> pending taker
+}
+
+func newSocksCallbackPool(records map[string]*config.SocksRecord) *socksCallbackPool {
+	return &socksCallbackPool{records: records, waiting: map[string]chan net.Conn{}}
+}
+
+// Listen starts accepting reverse connections on every distinct callback
+// address among the pool's backends.
+func (p *socksCallbackPool) Listen() error {
+	addrs := map[string]struct{}{}
+	for _, record := range p.records {
+		addrs[net.JoinHostPort(record.Host, strconv.Itoa(record.Port))] = struct{}{}
+	}
+
+	for addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			p.Close()
+			return fmt.Errorf("listen for socks callback on %s: %w", addr, err)
+		}
+		p.listeners = append(p.listeners, ln)
+		go p.accept(ln)
+	}
+	return nil
+}
+
+func (p *socksCallbackPool) Close() {
+	for _, ln := range p.listeners {
+		ln.Close()
+	}
+}
+
+func (p *socksCallbackPool) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go p.authenticate(conn)
+	}
+}
+
+// authenticate reads the deploy-time key every reverse connection opens
+// with, matches it against a deployed backend, and delivers the
+// connection to whichever relaySocksConn is currently waiting for that
+// backend.
+func (p *socksCallbackPool) authenticate(conn net.Conn) {
+	key := make([]byte, socks.KeyLength)
+	if _, err := io.ReadFull(conn, key); err != nil {
+		logrus.Debugf("socks callback: %s", err)
+		conn.Close()
+		return
+	}
+
+	for backend, record := range p.records {
+		if record.Key != string(key) {
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.waiting[backend]
+		if ok {
+			delete(p.waiting, backend)
+		}
+		p.mu.Unlock()
+
+		if !ok {
+			logrus.Debugf("socks callback for %s arrived with nothing waiting for it, dropping", backend)
+			conn.Close()
+			return
+		}
+		ch <- conn
+		return
+	}
+
+	logrus.Warn("socks callback with an unrecognized key rejected")
+	conn.Close()
+}
+
+// Take blocks until the deployed function for provider/region dials back
+// in, or callbackWaitTimeout elapses.
+func (p *socksCallbackPool) Take(provider, region string) (net.Conn, error) {
+	key := backendKey(provider, region)
+
+	ch := make(chan net.Conn, 1)
+	p.mu.Lock()
+	p.waiting[key] = ch
+	p.mu.Unlock()
+
+	select {
+	case conn := <-ch:
+		return conn, nil
+	case <-time.After(callbackWaitTimeout):
+		p.mu.Lock()
+		delete(p.waiting, key)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("timed out waiting for %s's deployed socks function to call back", key)
+	}
+}
+
+func backendKey(provider, region string) string {
+	return provider + "." + region
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// socksHandshake performs the server side of a RFC1928 SOCKS5 handshake on
+// conn, accepting clients that offer "no authentication required", and
+// returns the "host:port" the client asked to reach via a CONNECT request.
+// It exists so `scfproxy serve socks` can terminate the client locally and
+// decide, per dispatch.Picker, which deployed backend's callback
+// connection to relay the target through.
+func socksHandshake(conn net.Conn) (string, error) {
+	buf := make([]byte, 262)
+
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	if buf[0] != 0x05 {
+		return "", errors.New("unsupported socks version")
+	}
+	nMethods := int(buf[1])
+	if _, err := io.ReadFull(conn, buf[:nMethods]); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil {
+		return "", err
+	}
+
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return "", err
+	}
+	version, cmd, addrType := buf[0], buf[1], buf[3]
+	if version != 0x05 {
+		return "", errors.New("unsupported socks version")
+	}
+	if cmd != 0x01 {
+		return "", fmt.Errorf("unsupported socks command %d, only CONNECT is supported", cmd)
+	}
+
+	var host string
+	switch addrType {
+	case 0x01: // IPv4
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return "", err
+		}
+		host = net.IP(buf[:4]).String()
+	case 0x03: // domain name
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return "", err
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return "", err
+		}
+		host = string(buf[:n])
+	case 0x04: // IPv6
+		if _, err := io.ReadFull(conn, buf[:16]); err != nil {
+			return "", err
+		}
+		host = net.IP(buf[:16]).String()
+	default:
+		return "", fmt.Errorf("unsupported socks address type %d", addrType)
+	}
+
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	port := int(buf[0])<<8 | int(buf[1])
+
+	// success reply, binding the sentinel address since the "bound" address
+	// of a dispatched upstream isn't meaningful to the client.
+	if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// socksAuth is a SOCKS5 username/password credential, parsed from a
+// deploy-time "user:pass" record the way deploy socks's [--auth] flag
+// accepts it.
+type socksAuth struct {
+	User     string
+	Password string
+}
+
+// socksClientConnect performs the client side of a RFC1928 SOCKS5
+// handshake over conn, requesting a CONNECT to target. It exists because,
+// once the deployed socks function has dialed back to us, scfproxy is the
+// one speaking SOCKS5 as the client over that connection - the function
+// is the server - which golang.org/x/net/proxy.SOCKS5 doesn't support
+// over an already-established net.Conn.
+func socksClientConnect(conn net.Conn, target string, auth *socksAuth) error {
+	methods := []byte{0x00}
+	if auth != nil {
+		methods = []byte{0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return errors.New("unsupported socks version")
+	}
+
+	switch reply[1] {
+	case 0x00:
+		// no authentication required
+	case 0x02:
+		if auth == nil {
+			return errors.New("socks backend requires username/password authentication")
+		}
+		req := []byte{0x01, byte(len(auth.User))}
+		req = append(req, []byte(auth.User)...)
+		req = append(req, byte(len(auth.Password)))
+		req = append(req, []byte(auth.Password)...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		authReply := make([]byte, 2)
+		if _, err := io.ReadFull(conn, authReply); err != nil {
+			return err
+		}
+		if authReply[1] != 0x00 {
+			return errors.New("socks backend rejected username/password authentication")
+		}
+	default:
+		return errors.New("socks backend offered no acceptable authentication method")
+	}
+
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	req := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
+	req = append(req, []byte(host)...)
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("socks backend refused CONNECT: reply code %d", header[1])
+	}
+
+	var addrLen int
+	switch header[3] {
+	case 0x01:
+		addrLen = 4
+	case 0x03:
+		lenBuf := make([]byte, 1)
+		if _, err := io.ReadFull(conn, lenBuf); err != nil {
+			return err
+		}
+		addrLen = int(lenBuf[0])
+	case 0x04:
+		addrLen = 16
+	default:
+		return fmt.Errorf("unsupported socks address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, addrLen+2))
+	return err
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestSocksHandshake drives socksHandshake's server side by hand, byte for
+// byte, against a RFC1928 CONNECT request for a domain name target.
+func TestSocksHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan struct{})
+	var target string
+	var err error
+	go func() {
+		defer close(done)
+		target, err = socksHandshake(server)
+	}()
+
+	// greeting: version 5, 1 method, "no auth"
+	if _, werr := client.Write([]byte{0x05, 0x01, 0x00}); werr != nil {
+		t.Fatal(werr)
+	}
+	methodReply := make([]byte, 2)
+	if _, rerr := io.ReadFull(client, methodReply); rerr != nil {
+		t.Fatal(rerr)
+	}
+	if methodReply[0] != 0x05 || methodReply[1] != 0x00 {
+		t.Fatalf("method reply = %v, want [5 0]", methodReply)
+	}
+
+	// CONNECT request to example.com:443 via a domain-name address
+	host := "example.com"
+	req := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
+	req = append(req, []byte(host)...)
+	req = append(req, 0x01, 0xbb) // port 443
+	if _, werr := client.Write(req); werr != nil {
+		t.Fatal(werr)
+	}
+
+	reply := make([]byte, 10)
+	if _, rerr := io.ReadFull(client, reply); rerr != nil {
+		t.Fatal(rerr)
+	}
+	if reply[0] != 0x05 || reply[1] != 0x00 {
+		t.Fatalf("connect reply = %v, want success", reply)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("socksHandshake didn't return")
+	}
+	if err != nil {
+		t.Fatalf("socksHandshake returned error: %s", err)
+	}
+	if target != "example.com:443" {
+		t.Errorf("target = %q, want %q", target, "example.com:443")
+	}
+}
+
+// TestSocksClientConnect drives socksClientConnect's peer by hand, acting
+// as the RFC1928 server it expects the deployed socks function to be.
+func TestSocksClientConnect(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- socksClientConnect(client, "example.com:443", nil)
+	}()
+
+	greeting := make([]byte, 3)
+	if _, err := io.ReadFull(server, greeting); err != nil {
+		t.Fatal(err)
+	}
+	if greeting[0] != 0x05 || greeting[2] != 0x00 {
+		t.Fatalf("greeting = %v, want no-auth method offered", greeting)
+	}
+	if _, err := server.Write([]byte{0x05, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(server, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x05 || header[1] != 0x01 || header[3] != 0x03 {
+		t.Fatalf("connect request header = %v, want domain-name CONNECT", header)
+	}
+	host := make([]byte, header[4])
+	if _, err := io.ReadFull(server, host); err != nil {
+		t.Fatal(err)
+	}
+	if string(host) != "example.com" {
+		t.Errorf("requested host = %q, want %q", host, "example.com")
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(server, port); err != nil {
+		t.Fatal(err)
+	}
+	if int(port[0])<<8|int(port[1]) != 443 {
+		t.Errorf("requested port = %d, want 443", int(port[0])<<8|int(port[1]))
+	}
+
+	// success reply, binding the sentinel address
+	if _, err := server.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("socksClientConnect returned error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("socksClientConnect didn't return")
+	}
+}
+
+// TestSocksHandshakeAndClientConnect wires socksHandshake and
+// socksClientConnect together over a single net.Pipe - the former plays
+// the server role, the latter the client role of the same RFC1928
+// exchange - to check the wire format they agree on round-trips end to
+// end, the way relaySocksConn chains them across two different
+// connections in production.
+func TestSocksHandshakeAndClientConnect(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	serverDone := make(chan struct{})
+	var target string
+	var serverErr error
+	go func() {
+		defer close(serverDone)
+		target, serverErr = socksHandshake(a)
+	}()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		clientErr <- socksClientConnect(b, "example.com:443", nil)
+	}()
+
+	select {
+	case <-serverDone:
+	case <-time.After(time.Second):
+		t.Fatal("socksHandshake didn't return")
+	}
+	if serverErr != nil {
+		t.Fatalf("socksHandshake returned error: %s", serverErr)
+	}
+	if target != "example.com:443" {
+		t.Errorf("target = %q, want %q", target, "example.com:443")
+	}
+
+	select {
+	case err := <-clientErr:
+		if err != nil {
+			t.Fatalf("socksClientConnect returned error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("socksClientConnect didn't return")
+	}
+}
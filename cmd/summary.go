@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// deploySummary aggregates a deployHttp/deploySocks/deployReverse run's
+// outcome across every provider so the run ends with one structured
+// record instead of only scattered per-region log lines.
+type deploySummary struct {
+	module string
+
+	mu        sync.Mutex
+	succeeded map[string]int
+	failed    map[string][]string
+}
+
+func newDeploySummary(module string) *deploySummary {
+	return &deploySummary{
+		module:    module,
+		succeeded: map[string]int{},
+		failed:    map[string][]string{},
+	}
+}
+
+func (s *deploySummary) recordSuccess(provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.succeeded[provider]++
+}
+
+func (s *deploySummary) recordFailure(provider, region string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed[provider] = append(s.failed[provider], region)
+}
+
+// log prints the accumulated counts and failed regions as a single
+// structured record.
+func (s *deploySummary) log() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"module":    s.module,
+		"succeeded": s.succeeded,
+		"failed":    s.failed,
+	}).Info("deploy summary")
+}
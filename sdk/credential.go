@@ -0,0 +1,158 @@
+package sdk
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialSource identifies where a provider's credentials came from,
+// so callers can log it without ever logging the credential itself.
+type CredentialSource string
+
+const (
+	SourceFlag CredentialSource = "flag"
+	SourceEnv  CredentialSource = "env"
+	SourceFile CredentialSource = "file"
+)
+
+// Credential is a resolved credential together with the source it came
+// from. Raw holds whatever fields the provider's config section expects
+// (e.g. secret_id/secret_key), keyed the same way as the provider's YAML
+// config section.
+type Credential struct {
+	Source CredentialSource
+	Raw    map[string]string
+}
+
+// CredentialResolver resolves a provider's credentials from one
+// particular source.
+type CredentialResolver interface {
+	Resolve(provider string) (*Credential, bool)
+}
+
+// ChainResolver tries each underlying CredentialResolver in order and
+// returns the first one that yields a credential, modeled on Packer's
+// OpenStack AccessConfig chain of explicit flag, then environment, then a
+// shared credentials file.
+type ChainResolver struct {
+	resolvers []CredentialResolver
+}
+
+// NewChainResolver builds a ChainResolver over resolvers, tried in order.
+func NewChainResolver(resolvers ...CredentialResolver) *ChainResolver {
+	return &ChainResolver{resolvers: resolvers}
+}
+
+func (c *ChainResolver) Resolve(provider string) (*Credential, bool) {
+	for _, r := range c.resolvers {
+		if cred, ok := r.Resolve(provider); ok {
+			return cred, true
+		}
+	}
+	return nil, false
+}
+
+// FlagResolver resolves credentials passed explicitly on the command
+// line as repeated "provider.field=value" pairs, e.g.
+// --credential tencent.secret_id=AKID...
+type FlagResolver struct {
+	values map[string]map[string]string
+}
+
+// NewFlagResolver parses "provider.field=value" pairs as produced by a
+// repeatable --credential flag. Malformed pairs are skipped.
+func NewFlagResolver(pairs []string) *FlagResolver {
+	values := map[string]map[string]string{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		provider, field, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+		if values[provider] == nil {
+			values[provider] = map[string]string{}
+		}
+		values[provider][field] = value
+	}
+	return &FlagResolver{values: values}
+}
+
+func (f *FlagResolver) Resolve(provider string) (*Credential, bool) {
+	raw, ok := f.values[provider]
+	if !ok || len(raw) == 0 {
+		return nil, false
+	}
+	return &Credential{Source: SourceFlag, Raw: raw}, true
+}
+
+// providerEnvVars maps each provider to the environment variables that
+// can supply its credentials, and the config field each one fills in.
+// All of a provider's variables must be set for EnvResolver to use them.
+var providerEnvVars = map[string]map[string]string{
+	"tencent": {"TENCENT_SECRET_ID": "secret_id", "TENCENT_SECRET_KEY": "secret_key"},
+	"aliyun":  {"ALICLOUD_ACCESS_KEY": "access_key", "ALICLOUD_SECRET_KEY": "secret_key"},
+	"aws":     {"AWS_ACCESS_KEY_ID": "access_key_id", "AWS_SECRET_ACCESS_KEY": "secret_access_key"},
+}
+
+// EnvResolver resolves credentials from the provider-specific environment
+// variables in providerEnvVars, so the tool is usable in CI/containers
+// without mounting a config file.
+type EnvResolver struct{}
+
+func (EnvResolver) Resolve(provider string) (*Credential, bool) {
+	fields, ok := providerEnvVars[provider]
+	if !ok {
+		return nil, false
+	}
+
+	raw := map[string]string{}
+	for env, field := range fields {
+		value := os.Getenv(env)
+		if value == "" {
+			return nil, false
+		}
+		raw[field] = value
+	}
+	return &Credential{Source: SourceEnv, Raw: raw}, true
+}
+
+// FileResolver resolves credentials from a shared YAML credentials file,
+// keyed by provider name the same way the provider config file is.
+type FileResolver struct {
+	path string
+
+	once sync.Once
+	data map[string]map[string]string
+}
+
+// NewFileResolver builds a FileResolver that reads path lazily, on the
+// first Resolve call.
+func NewFileResolver(path string) *FileResolver {
+	return &FileResolver{path: path}
+}
+
+func (f *FileResolver) Resolve(provider string) (*Credential, bool) {
+	f.once.Do(func() {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			logrus.Debugf("shared credentials file %s not used: %s", f.path, err)
+			return
+		}
+		if err := yaml.Unmarshal(data, &f.data); err != nil {
+			logrus.Warningf("shared credentials file %s: %s", f.path, err)
+		}
+	})
+
+	raw, ok := f.data[provider]
+	if !ok || len(raw) == 0 {
+		return nil, false
+	}
+	return &Credential{Source: SourceFile, Raw: raw}, true
+}
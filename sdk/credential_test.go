@@ -0,0 +1,121 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlagResolver(t *testing.T) {
+	r := NewFlagResolver([]string{
+		"tencent.secret_id=AKID123",
+		"tencent.secret_key=SECRET",
+		"malformed",
+		"aliyun=missing-dot",
+	})
+
+	cred, ok := r.Resolve("tencent")
+	if !ok {
+		t.Fatal("Resolve(tencent) = false, want true")
+	}
+	if cred.Source != SourceFlag {
+		t.Errorf("Source = %q, want %q", cred.Source, SourceFlag)
+	}
+	if cred.Raw["secret_id"] != "AKID123" || cred.Raw["secret_key"] != "SECRET" {
+		t.Errorf("Raw = %v, want secret_id/secret_key pair", cred.Raw)
+	}
+
+	if _, ok := r.Resolve("aliyun"); ok {
+		t.Error("Resolve(aliyun) = true, want false for a malformed pair")
+	}
+	if _, ok := r.Resolve("aws"); ok {
+		t.Error("Resolve(aws) = true, want false for an unset provider")
+	}
+}
+
+func TestEnvResolver(t *testing.T) {
+	var r EnvResolver
+
+	if _, ok := r.Resolve("tencent"); ok {
+		t.Error("Resolve(tencent) = true, want false before env vars are set")
+	}
+
+	t.Setenv("TENCENT_SECRET_ID", "AKID123")
+	if _, ok := r.Resolve("tencent"); ok {
+		t.Error("Resolve(tencent) = true, want false with only one of two vars set")
+	}
+
+	t.Setenv("TENCENT_SECRET_KEY", "SECRET")
+	cred, ok := r.Resolve("tencent")
+	if !ok {
+		t.Fatal("Resolve(tencent) = false, want true once both vars are set")
+	}
+	if cred.Source != SourceEnv {
+		t.Errorf("Source = %q, want %q", cred.Source, SourceEnv)
+	}
+	if cred.Raw["secret_id"] != "AKID123" || cred.Raw["secret_key"] != "SECRET" {
+		t.Errorf("Raw = %v, want secret_id/secret_key pair", cred.Raw)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	contents := "tencent:\n  secret_id: AKID123\n  secret_key: SECRET\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewFileResolver(path)
+
+	cred, ok := r.Resolve("tencent")
+	if !ok {
+		t.Fatal("Resolve(tencent) = false, want true")
+	}
+	if cred.Source != SourceFile {
+		t.Errorf("Source = %q, want %q", cred.Source, SourceFile)
+	}
+	if cred.Raw["secret_id"] != "AKID123" || cred.Raw["secret_key"] != "SECRET" {
+		t.Errorf("Raw = %v, want secret_id/secret_key pair", cred.Raw)
+	}
+
+	if _, ok := r.Resolve("aws"); ok {
+		t.Error("Resolve(aws) = true, want false for a provider missing from the file")
+	}
+}
+
+func TestFileResolverMissingFile(t *testing.T) {
+	r := NewFileResolver(filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+
+	if _, ok := r.Resolve("tencent"); ok {
+		t.Error("Resolve(tencent) = true, want false when the file doesn't exist")
+	}
+}
+
+func TestChainResolver(t *testing.T) {
+	flag := NewFlagResolver([]string{"tencent.secret_id=FROM_FLAG"})
+
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	if err := os.WriteFile(path, []byte("tencent:\n  secret_id: FROM_FILE\n  secret_key: FROM_FILE\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file := NewFileResolver(path)
+
+	chain := NewChainResolver(flag, file)
+
+	// tencent has only secret_id set via the flag resolver, which still
+	// counts as a match - the chain stops at the first resolver that
+	// resolves anything at all, it doesn't merge across sources.
+	cred, ok := chain.Resolve("tencent")
+	if !ok {
+		t.Fatal("Resolve(tencent) = false, want true")
+	}
+	if cred.Source != SourceFlag {
+		t.Errorf("Source = %q, want %q (the flag resolver should win over the file)", cred.Source, SourceFlag)
+	}
+
+	// aws isn't set via flag, so the chain should fall through to the file
+	// resolver's "not found" and report no credential at all.
+	if _, ok := chain.Resolve("aws"); ok {
+		t.Error("Resolve(aws) = true, want false when no resolver in the chain has it")
+	}
+}
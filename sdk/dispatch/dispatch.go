@@ -0,0 +1,92 @@
+// Package dispatch implements the upstream-selection strategies used by
+// `scfproxy serve` to fan local traffic out across many deployed proxy
+// endpoints behind a single listener, the way clash picks an upstream out
+// of a proxy group.
+package dispatch
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/shimmeris/SCFProxy/sdk/health"
+)
+
+// Mode selects how Picker.Pick chooses an upstream Backend.
+type Mode string
+
+const (
+	RoundRobin Mode = "round-robin"
+	Random     Mode = "random"
+	Sticky     Mode = "sticky"
+	Latency    Mode = "latency"
+)
+
+// Backend is a single deployed proxy endpoint a Picker can select between.
+// Addr is module-specific: an API URL for the http module, a host:port for
+// the socks module.
+type Backend struct {
+	Provider string
+	Region   string
+	Addr     string
+	Auth     string
+}
+
+// Picker selects one Backend for each incoming request/connection,
+// according to its Mode.
+type Picker struct {
+	mode     Mode
+	backends []Backend
+	checker  *health.Checker // only consulted in Latency mode
+	counter  uint64
+}
+
+// NewPicker creates a Picker over backends. checker may be nil unless mode
+// is Latency, in which case it is used to prefer the lowest-latency
+// healthy backend, falling back to round-robin while no probe has
+// succeeded yet.
+func NewPicker(mode Mode, backends []Backend, checker *health.Checker) *Picker {
+	return &Picker{mode: mode, backends: backends, checker: checker}
+}
+
+// Pick returns the Backend to use. host is the client's requested target
+// host and is only consulted in Sticky mode.
+func (p *Picker) Pick(host string) (Backend, bool) {
+	if len(p.backends) == 0 {
+		return Backend{}, false
+	}
+
+	switch p.mode {
+	case Random:
+		return p.backends[rand.Intn(len(p.backends))], true
+	case Sticky:
+		return p.backends[stickyIndex(host, len(p.backends))], true
+	case Latency:
+		if p.checker != nil {
+			if best, ok := p.checker.Best(); ok {
+				if b, ok := p.find(best.Provider, best.Region); ok {
+					return b, true
+				}
+			}
+		}
+		fallthrough
+	default: // RoundRobin
+		n := atomic.AddUint64(&p.counter, 1)
+		return p.backends[int(n-1)%len(p.backends)], true
+	}
+}
+
+func (p *Picker) find(provider, region string) (Backend, bool) {
+	for _, b := range p.backends {
+		if b.Provider == provider && b.Region == region {
+			return b, true
+		}
+	}
+	return Backend{}, false
+}
+
+func stickyIndex(host string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return int(h.Sum32()) % n
+}
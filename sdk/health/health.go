@@ -0,0 +1,185 @@
+// Package health probes the proxies deployed by the http and reverse
+// modules over HTTP, tracking round-trip latency and consecutive probe
+// failures against a configurable target so callers can route to whichever
+// endpoint is currently fastest and healthy, rather than picking a region
+// by hand. Socks deployments expose no HTTP endpoint and are not probed,
+// and no success rate over time is kept.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Target identifies a single deployed proxy endpoint to probe.
+type Target struct {
+	Provider string
+	Region   string
+	API      string
+}
+
+// Status is the latest observed health of a Target.
+type Status struct {
+	Target
+	Latency time.Duration
+	Alive   bool
+	Fails   int
+}
+
+// Checker periodically probes a fixed set of Targets against a target URL
+// and keeps track of each one's latency and liveness.
+type Checker struct {
+	targetURL string
+	interval  time.Duration
+	maxFails  int
+	client    *http.Client
+
+	mu       sync.Mutex
+	statuses map[string]*Status
+}
+
+// NewChecker creates a Checker that probes targetURL through each Target
+// every interval, marking a Target dead after maxFails consecutive failed
+// probes and alive again as soon as one succeeds.
+func NewChecker(targets []Target, targetURL string, interval time.Duration, maxFails int) *Checker {
+	c := &Checker{
+		targetURL: targetURL,
+		interval:  interval,
+		maxFails:  maxFails,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		statuses:  make(map[string]*Status, len(targets)),
+	}
+	for _, t := range targets {
+		c.statuses[key(t)] = &Status{Target: t, Alive: true}
+	}
+	return c
+}
+
+func key(t Target) string {
+	return t.Provider + "." + t.Region + " " + t.API
+}
+
+// Run probes every Target once immediately, then again on every tick of
+// the configured interval, until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	c.probeAll()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.probeAll()
+		}
+	}
+}
+
+func (c *Checker) probeAll() {
+	c.mu.Lock()
+	targets := make([]Target, 0, len(c.statuses))
+	for _, s := range c.statuses {
+		targets = append(targets, s.Target)
+	}
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(targets))
+	for _, t := range targets {
+		go func(t Target) {
+			defer wg.Done()
+			c.probe(t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (c *Checker) probe(t Target) {
+	req, err := http.NewRequest(http.MethodGet, t.API, nil)
+	if err != nil {
+		c.recordFailure(t)
+		return
+	}
+	req.Header.Set("X-Scfproxy-Target", c.targetURL)
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	latency := time.Since(start)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		c.recordFailure(t)
+		return
+	}
+	resp.Body.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.statuses[key(t)]
+	s.Fails = 0
+	s.Alive = true
+	s.Latency = latency
+}
+
+func (c *Checker) recordFailure(t Target) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.statuses[key(t)]
+	s.Fails++
+	if s.Fails >= c.maxFails {
+		s.Alive = false
+	}
+}
+
+// Statuses returns a snapshot of every Target's status, sorted by
+// ascending latency.
+func (c *Checker) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]Status, 0, len(c.statuses))
+	for _, s := range c.statuses {
+		result = append(result, *s)
+	}
+	slices.SortFunc(result, func(a, b Status) bool {
+		return a.Latency < b.Latency
+	})
+	return result
+}
+
+// Best returns the lowest-latency Target that is currently alive, mirroring
+// the fallback/url-test selection clash does for its proxy groups. A
+// Target with no successful probe yet has no latency to rank by and is
+// never returned.
+func (c *Checker) Best() (Status, bool) {
+	for _, s := range c.Statuses() {
+		if s.Alive && s.Latency > 0 {
+			return s, true
+		}
+	}
+	return Status{}, false
+}
+
+// Handler serves the current Statuses as JSON at /status, and the single
+// best candidate (or 503 if none is healthy) at /best.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Statuses())
+	})
+	mux.HandleFunc("/best", func(w http.ResponseWriter, r *http.Request) {
+		best, ok := c.Best()
+		if !ok {
+			http.Error(w, "no healthy proxy available", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(best)
+	})
+	return mux
+}